@@ -0,0 +1,79 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cli
+
+import (
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const letsEncryptDirectoryURL = "https://acme-v02.api.letsencrypt.org/directory"
+
+// acmeHosts, acmeCacheDir, acmeEmail and acmeDirectoryURL configure an
+// autocert.Manager for the admin UI's HTTP listener. They are only
+// consulted when --acme-hosts is non-empty; the node-to-node RPC path
+// always uses the internal CA regardless of these flags.
+var (
+	acmeHosts        string
+	acmeCacheDir     string
+	acmeEmail        string
+	acmeDirectoryURL string
+)
+
+func init() {
+	f := startCmd.Flags()
+	f.StringVar(&acmeHosts, "acme-hosts", "",
+		"comma-separated list of hostnames to obtain and manage TLS certificates for "+
+			"via ACME, for the admin UI listener only; node-to-node RPC always uses the "+
+			"internal CA")
+	f.StringVar(&acmeCacheDir, "acme-cache-dir", "",
+		"directory under which ACME-issued certificates are cached; defaults to "+
+			"\"acme-certs\" under the first on-disk store")
+	f.StringVar(&acmeEmail, "acme-email", "",
+		"contact email address registered with the ACME account used to request certificates")
+	f.StringVar(&acmeDirectoryURL, "acme-directory-url", letsEncryptDirectoryURL,
+		"ACME directory URL used to request certificates")
+}
+
+// acmeEnabled returns whether --acme-hosts was specified.
+func acmeEnabled() bool {
+	return acmeHosts != ""
+}
+
+// newAutocertManager builds the autocert.Manager that serves and renews
+// ACME certificates for the hosts in --acme-hosts. storeDir is the path
+// of the first on-disk store, used to hold the certificate cache when
+// --acme-cache-dir isn't set.
+func newAutocertManager(storeDir string) *autocert.Manager {
+	cacheDir := acmeCacheDir
+	if cacheDir == "" {
+		cacheDir = filepath.Join(storeDir, "acme-certs")
+	}
+	rawHosts := strings.Split(acmeHosts, ",")
+	hosts := make([]string, len(rawHosts))
+	for i, h := range rawHosts {
+		hosts[i] = strings.TrimSpace(h)
+	}
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cacheDir),
+		HostPolicy: autocert.HostWhitelist(hosts...),
+		Email:      acmeEmail,
+		Client:     &acme.Client{DirectoryURL: acmeDirectoryURL},
+	}
+}
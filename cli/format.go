@@ -0,0 +1,60 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// outputFormat selects how result payloads (as opposed to logging, which
+// always goes to stderr via util/log) are rendered to stdout. It is
+// controlled by the global --format flag.
+var outputFormat string
+
+func init() {
+	cockroachCmd.PersistentFlags().StringVar(&outputFormat, "format", "table",
+		"result output format: table, json, or yaml")
+}
+
+// renderResult writes v to stdout in the format selected by --format. For
+// "table" it delegates to tableFn, which is expected to print a
+// human-readable tabwriter banner; for "json" and "yaml" it marshals v
+// directly so the output can be piped into tools like jq.
+func renderResult(v interface{}, tableFn func() error) error {
+	switch outputFormat {
+	case "", "table":
+		return tableFn()
+	case "json":
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Println(string(b))
+		return err
+	case "yaml":
+		b, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(b)
+		return err
+	default:
+		return fmt.Errorf("unknown --format %q: must be table, json, or yaml", outputFormat)
+	}
+}
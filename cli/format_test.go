@@ -0,0 +1,101 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureStdout runs fn with os.Stdout replaced by a pipe and returns
+// whatever fn wrote to it.
+func captureStdout(t *testing.T, fn func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = orig }()
+
+	fn()
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestRenderResult(t *testing.T) {
+	type payload struct {
+		Foo string `json:"foo" yaml:"foo"`
+	}
+	v := payload{Foo: "bar"}
+
+	defer func(prev string) { outputFormat = prev }(outputFormat)
+
+	testCases := []struct {
+		format      string
+		wantTable   bool
+		wantErr     bool
+		wantContain string
+	}{
+		{format: "", wantTable: true},
+		{format: "table", wantTable: true},
+		{format: "json", wantContain: `"foo": "bar"`},
+		{format: "yaml", wantContain: "foo: bar"},
+		{format: "bogus", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			outputFormat = tc.format
+
+			var tableCalled bool
+			tableFn := func() error {
+				tableCalled = true
+				return nil
+			}
+
+			var err error
+			out := captureStdout(t, func() {
+				err = renderResult(v, tableFn)
+			})
+
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for format %q, got nil", tc.format)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("renderResult(%q) returned unexpected error: %s", tc.format, err)
+			}
+			if tableCalled != tc.wantTable {
+				t.Fatalf("renderResult(%q): tableFn called = %v, want %v", tc.format, tableCalled, tc.wantTable)
+			}
+			if tc.wantContain != "" && !strings.Contains(out, tc.wantContain) {
+				t.Fatalf("renderResult(%q): output %q does not contain %q", tc.format, out, tc.wantContain)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2015 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cli
+
+import (
+	"time"
+
+	"github.com/coreos/go-systemd/daemon"
+
+	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/cockroachdb/cockroach/util/stop"
+)
+
+// sdNotify forwards state to the systemd notification socket named by
+// $NOTIFY_SOCKET. It is a silent no-op when that variable is unset, which is
+// the case whenever the process isn't supervised by systemd (or is running
+// under a unit that isn't Type=notify).
+func sdNotify(state string) {
+	if ok, err := daemon.SdNotify(false, state); err != nil {
+		log.Warningf("sd_notify failed: %s", err)
+	} else if ok && log.V(1) {
+		log.Infof("sd_notify: %s", state)
+	}
+}
+
+// startSdNotifyWatchdog starts a goroutine that pings the systemd watchdog
+// at half of $WATCHDOG_USEC, as required by the sd_watchdog_enabled(3)
+// contract, so that units configured with WatchdogSec= don't get killed by
+// systemd while the node is healthy. It is a no-op if $WATCHDOG_USEC is
+// unset or the node isn't running under systemd supervision.
+func startSdNotifyWatchdog(stopper *stop.Stopper) {
+	interval, err := daemon.SdWatchdogEnabled(false)
+	if err != nil || interval == 0 {
+		return
+	}
+	stopper.RunWorker(func() {
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sdNotify(daemon.SdNotifyWatchdog)
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+	if log.V(1) {
+		log.Infof("sd_notify watchdog enabled, pinging every %s", interval/2)
+	}
+}
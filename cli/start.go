@@ -28,6 +28,8 @@ import (
 	"text/tabwriter"
 	"time"
 
+	"github.com/coreos/go-systemd/daemon"
+
 	"github.com/cockroachdb/cockroach/client"
 	"github.com/cockroachdb/cockroach/security"
 	"github.com/cockroachdb/cockroach/server"
@@ -37,6 +39,7 @@ import (
 	"github.com/cockroachdb/cockroach/util/stop"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // cliContext is the CLI Context used for the command-line client.
@@ -77,6 +80,32 @@ func getJSON(hostport, path string, v interface{}) error {
 	return util.GetJSON(httpClient, cliContext.HTTPRequestScheme(), hostport, path, v)
 }
 
+// firstOnDiskStorePath returns the path of the first non-memory store in
+// storeSpecs, and false if every store is in-memory. Used to anchor
+// per-node state (the default log directory, the ACME certificate cache)
+// to a real on-disk location.
+func firstOnDiskStorePath(storeSpecs []engine.StoreSpec) (string, bool) {
+	for _, spec := range storeSpecs {
+		if spec.Attrs == "mem" {
+			continue
+		}
+		return spec.Path, true
+	}
+	return "", false
+}
+
+// insecureAutoInit, when set, allows a brand-new node started without
+// --join to bootstrap itself immediately instead of waiting for an
+// explicit `cockroach init`.
+var insecureAutoInit bool
+
+func init() {
+	startCmd.Flags().BoolVar(&insecureAutoInit, "insecure-auto-init", false,
+		"automatically bootstrap a new cluster if this node is not joining "+
+			"one and has not yet been initialized; by default, a fresh node "+
+			"waits for `cockroach init` to be run against it")
+}
+
 // startCmd starts a node by initializing the stores and joining
 // the cluster.
 var startCmd = &cobra.Command{
@@ -86,8 +115,10 @@ var startCmd = &cobra.Command{
 Start a CockroachDB node, which will export data from one or more
 storage devices, specified via the --stores flag.
 
-If no cluster exists yet and this is the first node, no additional
-flags are required. If the cluster already exists, and this node is
+If no cluster exists yet and this is the first node, run 'cockroach
+init' against it once it is up to perform the one-time cluster
+bootstrap (pass --insecure-auto-init to skip this and bootstrap
+immediately instead). If the cluster already exists, and this node is
 uninitialized, specify the --join flag to point to any healthy node
 (or list of nodes) already part of the cluster.
 `,
@@ -96,6 +127,16 @@ uninitialized, specify the --join flag to point to any healthy node
 	RunE:         runStart,
 }
 
+// startResult is the structured payload emitted by runStart when
+// --format is json or yaml, in lieu of the tabwriter banner.
+type startResult struct {
+	Build    string   `json:"build" yaml:"build"`
+	AdminURL string   `json:"admin_url" yaml:"admin_url"`
+	SQLURL   string   `json:"sql_url" yaml:"sql_url"`
+	LogDir   string   `json:"log_dir" yaml:"log_dir"`
+	Stores   []string `json:"stores" yaml:"stores"`
+}
+
 // runStart starts the cockroach node using --stores as the list of
 // storage devices ("stores") on this machine and --join as the list
 // of other active nodes used to join this node to the cockroach
@@ -114,18 +155,14 @@ func runStart(_ *cobra.Command, _ []string) error {
 	// by changing the log-dir flag to keep track of whether it has been set or
 	// not. Doesn't seem urgent to do (yet).
 	if f := flag.Lookup("log-dir"); f.Value.String() == os.TempDir() {
-		for _, spec := range storeSpecs {
-			if spec.Attrs == "mem" {
-				continue
-			}
-			dir := filepath.Join(spec.Path, "logs")
+		if storeDir, ok := firstOnDiskStorePath(storeSpecs); ok {
+			dir := filepath.Join(storeDir, "logs")
 			if err := os.MkdirAll(dir, 0755); err != nil {
 				return err
 			}
 			if err := f.Value.Set(dir); err != nil {
 				return err
 			}
-			break
 		}
 	}
 
@@ -144,6 +181,20 @@ func runStart(_ *cobra.Command, _ []string) error {
 		return fmt.Errorf("failed to initialize node: %s", err)
 	}
 
+	// Unless told to auto-bootstrap, a node that isn't joining an existing
+	// cluster and hasn't been initialized yet waits for an explicit
+	// `cockroach init` rather than silently bootstrapping a new cluster on
+	// first start.
+	cliContext.Context.WaitForInit = !insecureAutoInit
+
+	if acmeEnabled() {
+		storeDir, ok := firstOnDiskStorePath(storeSpecs)
+		if !ok {
+			return fmt.Errorf("--acme-hosts requires at least one on-disk store")
+		}
+		cliContext.Context.ACMEManager = newAutocertManager(storeDir)
+	}
+
 	log.Info("starting cockroach node")
 	s, err := server.NewServer(&cliContext.Context, stopper)
 	if err != nil {
@@ -154,18 +205,41 @@ func runStart(_ *cobra.Command, _ []string) error {
 		return fmt.Errorf("cockroach server exited with error: %s", err)
 	}
 
-	tw := tabwriter.NewWriter(os.Stdout, 2, 1, 2, ' ', 0)
-	fmt.Fprintf(tw, "build:\t%s @ %s (%s)\n", info.Tag, info.Time, info.Vers)
-	fmt.Fprintf(tw, "admin:\t%s\n", cliContext.AdminURL())
-	fmt.Fprintf(tw, "sql:\t%s\n", cliContext.PGURL(connUser))
-	fmt.Fprintf(tw, "logs:\t%s\n", flag.Lookup("log-dir").Value)
+	stores := make([]string, len(storeSpecs))
 	for i, spec := range storeSpecs {
-		fmt.Fprintf(tw, "store[%d]:\t%s\n", i, spec.Name)
+		stores[i] = spec.Name
 	}
-	if err := tw.Flush(); err != nil {
+	result := startResult{
+		Build:    fmt.Sprintf("%s @ %s (%s)", info.Tag, info.Time, info.Vers),
+		AdminURL: cliContext.AdminURL(),
+		SQLURL:   cliContext.PGURL(connUser),
+		LogDir:   flag.Lookup("log-dir").Value.String(),
+		Stores:   stores,
+	}
+	if err := renderResult(result, func() error {
+		tw := tabwriter.NewWriter(os.Stdout, 2, 1, 2, ' ', 0)
+		fmt.Fprintf(tw, "build:\t%s\n", result.Build)
+		fmt.Fprintf(tw, "admin:\t%s\n", result.AdminURL)
+		fmt.Fprintf(tw, "sql:\t%s\n", result.SQLURL)
+		fmt.Fprintf(tw, "logs:\t%s\n", result.LogDir)
+		for i, store := range stores {
+			fmt.Fprintf(tw, "store[%d]:\t%s\n", i, store)
+		}
+		if acmeEnabled() {
+			fmt.Fprintf(tw, "acme hosts:\t%s\n", acmeHosts)
+			fmt.Fprintf(tw, "certs:\t%s (node-to-node and SQL)\n", cliContext.Certs)
+		} else {
+			fmt.Fprintf(tw, "certs:\t%s\n", cliContext.Certs)
+		}
+		return tw.Flush()
+	}); err != nil {
 		return err
 	}
 
+	sdNotify(daemon.SdNotifyReady)
+	sdNotify("STATUS=admin: " + cliContext.AdminURL() + ", sql: " + cliContext.PGURL(connUser))
+	startSdNotifyWatchdog(stopper)
+
 	signalCh := make(chan os.Signal, 1)
 	signal.Notify(signalCh, os.Interrupt, os.Kill)
 	// TODO(spencer): move this behind a build tag.
@@ -179,6 +253,8 @@ func runStart(_ *cobra.Command, _ []string) error {
 		go s.Stop()
 	}
 
+	sdNotify(daemon.SdNotifyReloading)
+	sdNotify("STATUS=draining")
 	log.Info("initiating graceful shutdown of server")
 
 	go func() {
@@ -200,15 +276,43 @@ func runStart(_ *cobra.Command, _ []string) error {
 	select {
 	case <-signalCh:
 		log.Warningf("second signal received, initiating hard shutdown")
-	case <-time.After(time.Minute):
-		log.Warningf("time limit reached, initiating hard shutdown")
+	case <-time.After(drainDeadline):
+		log.Warningf("drain deadline of %s reached, initiating hard shutdown", drainDeadline)
 	case <-stopper.IsStopped():
 		log.Infof("server drained and shutdown completed")
 	}
+	sdNotify(daemon.SdNotifyStopping)
 	log.Flush()
 	return nil
 }
 
+// initCmd command bootstraps a new cluster.
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "bootstrap a new cluster",
+	Long: `
+Perform a one-time bootstrap of a new cluster. Run this once all the
+nodes of the cluster have been started with 'cockroach start' and are
+waiting for initialization (any node not started with --join, unless
+--insecure-auto-init was passed to it). It is only safe to run this
+command once per cluster.
+`,
+	SilenceUsage: true,
+	RunE:         panicGuard(runInit),
+}
+
+// runInit initializes the cluster by connecting to the admin endpoint
+// of an unbootstrapped node, the same way runQuit connects to request
+// a shutdown.
+func runInit(_ *cobra.Command, _ []string) {
+	admin := client.NewAdminClient(&cliContext.Context.Context, cliContext.Addr, client.Init)
+	body, err := admin.Get()
+	if err != nil {
+		panicf("cluster initialization failed: %s", err)
+	}
+	fmt.Printf("cluster successfully initialized: %s\n", body)
+}
+
 // exterminateCmd command shuts down the node server and
 // destroys all data held by the node.
 var exterminateCmd = &cobra.Command{
@@ -230,18 +334,65 @@ func runExterminate(_ *cobra.Command, _ []string) {
 		panicf("failed to initialize context: %s", err)
 	}
 
-	runQuit(nil, nil)
+	// Unlike the interactive `quit` command, exterminate must still reach
+	// the destroy loop below even if the node didn't finish draining
+	// within the deadline, so it uses doQuit directly instead of
+	// runQuit and ignores the exit-on-timeout behavior built for `quit`.
+	doQuit(false)
 
 	// Exterminate all data held in specified stores.
+	destroyed := []string{}
 	for _, e := range cliContext.Engines {
 		if rocksdb, ok := e.(*engine.RocksDB); ok {
 			log.Infof("exterminating data from store %s", e)
 			if err := rocksdb.Destroy(); err != nil {
 				panicf("unable to destroy store %s: %s", e, err)
 			}
+			destroyed = append(destroyed, rocksdb.String())
 		}
 	}
 	log.Infof("exterminated all data from stores %s", cliContext.Engines)
+
+	if err := renderResult(destroyed, func() error {
+		fmt.Printf("exterminated all data from stores %s\n", destroyed)
+		return nil
+	}); err != nil {
+		panicf("%s", err)
+	}
+}
+
+// drainDeadline bounds how long a node (whether draining via `quit` or
+// shutting down from a signal in runStart) waits for in-flight requests
+// and lease transfers to finish before forcing a hard stop. drainWait is
+// the minimum time to spend advertising as draining (requests rejected,
+// listeners still open) before closing listeners, giving load balancers
+// time to notice and stop sending traffic. hardQuit skips draining
+// entirely and requests an immediate hard stop.
+var (
+	drainDeadline time.Duration
+	drainWait     time.Duration
+	hardQuit      bool
+)
+
+// registerDrainDeadlineFlag binds --drain-deadline to fs. It is registered
+// on both startCmd and quitCmd: runStart uses it in place of the old
+// hard-coded one-minute shutdown deadline, and runQuit/runExterminate use
+// it to bound how long they wait for a drain to complete.
+func registerDrainDeadlineFlag(fs *pflag.FlagSet) {
+	fs.DurationVar(&drainDeadline, "drain-deadline", time.Minute,
+		"maximum time to wait for the node to drain before forcing a hard shutdown")
+}
+
+func init() {
+	registerDrainDeadlineFlag(startCmd.Flags())
+
+	f := quitCmd.Flags()
+	registerDrainDeadlineFlag(f)
+	f.DurationVar(&drainWait, "drain-wait", 0,
+		"minimum time to advertise as draining before closing listeners, "+
+			"so load balancers have time to notice and stop routing traffic here")
+	f.BoolVar(&hardQuit, "hard", false,
+		"skip draining and request an immediate hard shutdown")
 }
 
 // quitCmd command shuts down the node server.
@@ -251,20 +402,106 @@ var quitCmd = &cobra.Command{
 	Long: `
 Shutdown the server. The first stage is drain, where any new requests
 will be ignored by the server. When all extant requests have been
-completed, the server exits.
+completed, the server exits. Progress is polled from the node's drain
+status endpoint and, with --format=table, streamed to stdout; if --drain-deadline elapses
+before the node finishes draining, a hard stop is requested and quit
+exits non-zero.
 `,
 	SilenceUsage: true,
 	RunE:         panicGuard(runQuit),
 }
 
-// runQuit accesses the quit shutdown path.
-func runQuit(_ *cobra.Command, _ []string) {
+// quitResult is the structured payload emitted by runQuit when --format
+// is json or yaml.
+type quitResult struct {
+	Status          string        `json:"status" yaml:"status"`
+	DrainedRequests int           `json:"drained_requests" yaml:"drained_requests"`
+	Duration        time.Duration `json:"duration" yaml:"duration"`
+}
+
+func printQuitResult(result quitResult) {
+	if err := renderResult(result, func() error {
+		fmt.Printf("%s: %d requests drained in %s\n", result.Status, result.DrainedRequests, result.Duration)
+		return nil
+	}); err != nil {
+		panicf("%s", err)
+	}
+}
+
+// doQuit implements the drain/hard-shutdown handshake shared by runQuit
+// and runExterminate. Unless --hard is passed, it requests a drain and
+// polls the node's drain-status endpoint, printing progress (in table
+// format only) until the node finishes draining or --drain-deadline elapses. When exitOnDeadline
+// is true (the interactive `quit` path), reaching the deadline without
+// finishing drain prints the result and exits the process non-zero;
+// runExterminate passes false so it can proceed to destroy stores even
+// after a forced hard stop.
+func doQuit(exitOnDeadline bool) quitResult {
+	start := time.Now()
 	admin := client.NewAdminClient(&cliContext.Context.Context, cliContext.Addr, client.Quit)
-	body, err := admin.Get()
-	// TODO(tschottdorf): needs cleanup. An error here can happen if the shutdown
-	// happened faster than the HTTP request made it back.
-	if err != nil {
-		panicf("shutdown node error: %s", err)
+
+	if hardQuit {
+		if _, err := admin.Post(&client.QuitRequest{Hard: true}); err != nil {
+			panicf("hard shutdown request failed: %s", err)
+		}
+		return quitResult{Status: "stopped", Duration: time.Since(start)}
 	}
-	fmt.Printf("node drained and shutdown: %s\n", body)
+
+	if _, err := admin.Post(&client.QuitRequest{DrainWait: drainWait}); err != nil {
+		panicf("drain request failed: %s", err)
+	}
+
+	deadline := time.After(drainDeadline)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			var status client.DrainStatus
+			if err := getJSON(cliContext.Addr, client.DrainStatusPath, &status); err != nil {
+				// The drain we're polling for may have finished and closed
+				// the node's listeners between this tick firing and our
+				// request landing, so a connection failure here is at
+				// least as likely to mean "already drained" as "broken".
+				// Give it one short grace period to settle before
+				// deciding: if the endpoint is still unreachable, treat
+				// the drain as having completed rather than panicking on
+				// what is actually the common, successful case.
+				time.Sleep(200 * time.Millisecond)
+				if err := getJSON(cliContext.Addr, client.DrainStatusPath, &status); err != nil {
+					return quitResult{Status: "drained", Duration: time.Since(start)}
+				}
+			}
+			if outputFormat == "" || outputFormat == "table" {
+				fmt.Printf("draining: %d requests in-flight, %d leases to transfer\n",
+					status.InFlightRequests, status.LeasesToTransfer)
+			}
+			if status.Drained {
+				return quitResult{
+					Status:          "drained",
+					DrainedRequests: status.InFlightRequests,
+					Duration:        time.Since(start),
+				}
+			}
+		case <-deadline:
+			if outputFormat == "" || outputFormat == "table" {
+				fmt.Println("drain deadline exceeded, requesting hard shutdown")
+			}
+			if _, err := admin.Post(&client.QuitRequest{Hard: true}); err != nil {
+				panicf("hard shutdown request failed: %s", err)
+			}
+			result := quitResult{Status: "hard-stopped", Duration: time.Since(start)}
+			if exitOnDeadline {
+				printQuitResult(result)
+				os.Exit(1)
+			}
+			return result
+		}
+	}
+}
+
+// runQuit accesses the quit shutdown path, printing the outcome in the
+// format selected by --format.
+func runQuit(_ *cobra.Command, _ []string) {
+	printQuitResult(doQuit(true))
 }